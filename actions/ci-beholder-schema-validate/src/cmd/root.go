@@ -1,31 +1,94 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
+// Exit codes returned by the ci-beholder-schema-validate binary.
+const (
+	// ExitOK means no findings at or above the --fail-on threshold.
+	ExitOK = 0
+	// ExitFindings means at least one finding is at or above the --fail-on threshold.
+	ExitFindings = 1
+	// ExitUsage means the command line was invalid.
+	ExitUsage = 2
+	// ExitIO means a file could not be read or a git operation failed.
+	ExitIO = 3
+)
+
+// exitError carries the process exit code alongside the underlying error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func usageError(err error) error {
+	return &exitError{code: ExitUsage, err: err}
+}
+
+func ioError(err error) error {
+	return &exitError{code: ExitIO, err: err}
+}
 
 var rootCmd = &cobra.Command{
-	Use:   "ci-beholder-schema-validate",
-	Short: "Schema validation",
-	Long: `Schema validation`,
+	Use:           "ci-beholder-schema-validate",
+	Short:         "Schema validation",
+	Long:          `Schema validation`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		sev, err := parseSeverity(failOn)
+		if err != nil {
+			return usageError(err)
+		}
+		failOnSeverity = sev
+		return nil
+	},
 }
 
 func Execute() {
+	os.Exit(execute())
+}
+
+func execute() int {
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err == nil {
+		return ExitOK
 	}
+
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) {
+		// Errors not raised by our own commands come from cobra itself:
+		// unknown commands, unknown flags or missing required flags.
+		exitErr = &exitError{code: ExitUsage, err: err}
+	}
+	if exitErr.code != ExitFindings {
+		fmt.Fprintln(os.Stderr, "Error:", exitErr.err)
+	}
+	return exitErr.code
 }
 
-var beholderFilePath string
+var (
+	beholderFilePath string
+	failOn           string
+	failOnSeverity   severity
+)
 
 func init() {
-	
 	// add persistent flag for beholder file path
 	rootCmd.PersistentFlags().StringVarP(&beholderFilePath, "beholder-file", "f", "", "beholder file path")
 	_ = rootCmd.MarkPersistentFlagRequired("beholder-file")
 
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", severityError.String(), "minimum finding severity that fails the run (error, warning or notice)")
 }
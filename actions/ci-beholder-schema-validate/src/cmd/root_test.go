@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// resetFlags restores every flag to its default so that test cases sharing rootCmd do not
+// see each other's flags.
+func resetFlags(t *testing.T) {
+	t.Helper()
+	for _, fs := range []*pflag.FlagSet{rootCmd.PersistentFlags(), validateCmd.Flags()} {
+		fs.VisitAll(func(f *pflag.Flag) {
+			if err := f.Value.Set(f.DefValue); err != nil {
+				t.Fatalf("resetting --%s: %v", f.Name, err)
+			}
+			f.Changed = false
+		})
+	}
+}
+
+func TestExecuteExitCodes(t *testing.T) {
+	beholderFile := filepath.Join(t.TempDir(), "beholder.yaml")
+	if err := os.WriteFile(beholderFile, []byte("domain: test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missingFile := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "Valid", args: []string{"validate", "-f", beholderFile}, want: ExitOK},
+		{name: "Fail On Notice", args: []string{"validate", "-f", beholderFile, "--fail-on", "notice"}, want: ExitOK},
+		{name: "Missing Beholder File Flag", args: []string{"validate"}, want: ExitUsage},
+		{name: "Invalid Fail On", args: []string{"validate", "-f", beholderFile, "--fail-on", "fatal"}, want: ExitUsage},
+		{name: "Unknown Flag", args: []string{"validate", "-f", beholderFile, "--bogus"}, want: ExitUsage},
+		{name: "Unknown Command", args: []string{"bogus", "-f", beholderFile}, want: ExitUsage},
+		{name: "Unreadable Beholder File", args: []string{"validate", "-f", missingFile}, want: ExitIO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			rootCmd.SetArgs(tt.args)
+			t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+			if got := execute(); got != tt.want {
+				t.Errorf("execute(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import "fmt"
+
+// severity ranks findings so that --fail-on can decide which ones fail the run.
+type severity int
+
+const (
+	severityNotice severity = iota
+	severityWarning
+	severityError
+)
+
+func (s severity) String() string {
+	switch s {
+	case severityNotice:
+		return "notice"
+	case severityWarning:
+		return "warning"
+	case severityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+func parseSeverity(s string) (severity, error) {
+	switch s {
+	case "notice":
+		return severityNotice, nil
+	case "warning":
+		return severityWarning, nil
+	case "error":
+		return severityError, nil
+	default:
+		return 0, fmt.Errorf("invalid --fail-on value %q: must be one of error, warning or notice", s)
+	}
+}
+
+// finding is a single problem reported against a schema. No checks report findings yet.
+type finding struct {
+	severity severity
+	message  string
+}
+
+// countFailing returns how many findings are at or above the threshold.
+func countFailing(findings []finding, threshold severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.severity >= threshold {
+			n++
+		}
+	}
+	return n
+}
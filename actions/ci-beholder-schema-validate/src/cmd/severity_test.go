@@ -0,0 +1,45 @@
+package cmd
+
+import "testing"
+
+func TestParseSeverity(t *testing.T) {
+	for _, sev := range []severity{severityNotice, severityWarning, severityError} {
+		got, err := parseSeverity(sev.String())
+		if err != nil || got != sev {
+			t.Errorf("parseSeverity(%q) = %v, %v, want %v, nil", sev.String(), got, err, sev)
+		}
+	}
+	if _, err := parseSeverity("fatal"); err == nil {
+		t.Errorf("parseSeverity(%q) error = nil, want error", "fatal")
+	}
+}
+
+func TestCountFailing(t *testing.T) {
+	findings := []finding{
+		{severity: severityNotice, message: "notice"},
+		{severity: severityWarning, message: "warning"},
+		{severity: severityWarning, message: "warning"},
+		{severity: severityError, message: "error"},
+	}
+
+	tests := []struct {
+		name      string
+		findings  []finding
+		threshold severity
+		want      int
+	}{
+		{name: "Error", findings: findings, threshold: severityError, want: 1},
+		{name: "Warning", findings: findings, threshold: severityWarning, want: 3},
+		{name: "Notice", findings: findings, threshold: severityNotice, want: 4},
+		{name: "Only Notices Below Error", findings: findings[:1], threshold: severityError, want: 0},
+		{name: "No Findings", findings: nil, threshold: severityNotice, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countFailing(tt.findings, tt.threshold); got != tt.want {
+				t.Errorf("countFailing(%s) = %d, want %d", tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
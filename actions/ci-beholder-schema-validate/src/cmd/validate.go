@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -11,22 +10,23 @@ import (
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate schemas",
-	Long: `Validate schemas`,
-	Run: runValidateCmd,
+	Long:  `Validate schemas`,
+	RunE:  runValidateCmd,
 }
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
 }
 
-func runValidateCmd(cmd *cobra.Command, args []string) {
+func runValidateCmd(cmd *cobra.Command, args []string) error {
+	// Flags have been parsed by now, so any further error is not a usage problem.
+	cmd.SilenceUsage = true
 
 	beholderFileContents, err := os.ReadFile(beholderFilePath)
 	if err != nil {
-		log.Panic(err)
+		return ioError(err)
 	}
 
 	fmt.Printf("beholderFileContents: %s", beholderFileContents)
-
+	return nil
 }
-
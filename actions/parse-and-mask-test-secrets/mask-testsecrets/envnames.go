@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// posixEnvNameRe matches portable environment variable names as defined by POSIX.
+var posixEnvNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// deniedEnvNames are variables that change how later steps, shells, interpreters or the
+// dynamic linker behave. Exporting them through GITHUB_ENV could hijack later steps.
+var deniedEnvNames = map[string]bool{
+	"BASH_ENV":          true,
+	"BASHOPTS":          true,
+	"CDPATH":            true,
+	"ENV":               true,
+	"GCONV_PATH":        true,
+	"GIT_ASKPASS":       true,
+	"GIT_CONFIG_GLOBAL": true,
+	"GIT_CONFIG_SYSTEM": true,
+	"GIT_EXEC_PATH":     true,
+	"GIT_SSH":           true,
+	"GIT_SSH_COMMAND":   true,
+	"GOFLAGS":           true,
+	"HOME":              true,
+	"HOSTALIASES":       true,
+	"IFS":               true,
+	"JAVA_TOOL_OPTIONS": true,
+	"NODE_OPTIONS":      true,
+	"NODE_PATH":         true,
+	"PATH":              true,
+	"PERL5LIB":          true,
+	"PERL5OPT":          true,
+	"PROMPT_COMMAND":    true,
+	"PS4":               true,
+	"PYTHONPATH":        true,
+	"PYTHONSTARTUP":     true,
+	"RUBYLIB":           true,
+	"RUBYOPT":           true,
+	"SHELL":             true,
+	"SHELLOPTS":         true,
+	"SSH_ASKPASS":       true,
+	"_JAVA_OPTIONS":     true,
+}
+
+// deniedEnvPrefixes are prefixes of variables reserved for GitHub Actions or read by the
+// dynamic linker.
+var deniedEnvPrefixes = []string{
+	"ACTIONS_",
+	"BASH_FUNC_",
+	"DYLD_",
+	"GIT_CONFIG_",
+	"GITHUB_",
+	"LD_",
+	"RUNNER_",
+}
+
+// EnvNameError reports a variable that must not be exported.
+type EnvNameError struct {
+	Name   string
+	Line   int // Line where the variable is defined, or 0 if unknown
+	Reason string
+}
+
+// Error implements the error interface for EnvNameError.
+func (e *EnvNameError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("invalid environment variable name %q in line: %d. %s", e.Name, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("invalid environment variable name %q. %s", e.Name, e.Reason)
+}
+
+// validateEnvNames checks that every variable has a POSIX name that is not on the
+// deny-list and, if allowedPrefixes is not empty, starts with one of them. All invalid
+// names are reported together.
+func validateEnvNames(vars []secretVar, allowedPrefixes []string) error {
+	var errs []error
+	for _, v := range vars {
+		if reason := invalidEnvNameReason(v.Key, allowedPrefixes); reason != "" {
+			errs = append(errs, &EnvNameError{Name: v.Key, Line: v.Line, Reason: reason})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func invalidEnvNameReason(name string, allowedPrefixes []string) string {
+	if !posixEnvNameRe.MatchString(name) {
+		return "Names must consist of letters, digits and underscores and must not start with a digit."
+	}
+
+	// Names are compared case-insensitively because they are on Windows runners.
+	upper := strings.ToUpper(name)
+	if deniedEnvNames[upper] {
+		return "Setting it could change the behaviour of later steps."
+	}
+	for _, prefix := range deniedEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return fmt.Sprintf("Names starting with %s are reserved.", prefix)
+		}
+	}
+
+	if len(allowedPrefixes) == 0 {
+		return ""
+	}
+	for _, prefix := range allowedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Names must start with one of: %s.", strings.Join(allowedPrefixes, ", "))
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateEnvNames(t *testing.T) {
+	tests := []struct {
+		name            string
+		vars            []secretVar
+		allowedPrefixes []string
+		invalid         []string
+	}{
+		{
+			name:    "Valid Names",
+			vars:    []secretVar{{Key: "E2E_TEST_URL"}, {Key: "_PRIVATE"}, {Key: "lower_case1"}},
+			invalid: nil,
+		},
+		{
+			name: "Non-POSIX Names",
+			vars: []secretVar{
+				{Key: "1ST_KEY", Line: 1},
+				{Key: "KEY-NAME", Line: 2},
+				{Key: "KEY.NAME", Line: 3},
+				{Key: "KEYÄ", Line: 4},
+			},
+			invalid: []string{"1ST_KEY", "KEY-NAME", "KEY.NAME", "KEYÄ"},
+		},
+		{
+			name: "Denied Names",
+			vars: []secretVar{
+				{Key: "PATH"},
+				{Key: "LD_PRELOAD"},
+				{Key: "NODE_OPTIONS"},
+				{Key: "BASH_ENV"},
+				{Key: "path"},
+				{Key: "GITHUB_TOKEN"},
+				{Key: "github_output"},
+				{Key: "RUNNER_TEMP"},
+				{Key: "ACTIONS_RUNTIME_TOKEN"},
+				{Key: "DYLD_INSERT_LIBRARIES"},
+				{Key: "BASH_FUNC_ls%%"},
+				{Key: "PATHS"},
+			},
+			invalid: []string{
+				"PATH",
+				"LD_PRELOAD",
+				"NODE_OPTIONS",
+				"BASH_ENV",
+				"path",
+				"GITHUB_TOKEN",
+				"github_output",
+				"RUNNER_TEMP",
+				"ACTIONS_RUNTIME_TOKEN",
+				"DYLD_INSERT_LIBRARIES",
+				"BASH_FUNC_ls%%",
+			},
+		},
+		{
+			name:            "Allowed Prefixes",
+			vars:            []secretVar{{Key: "E2E_TEST_URL"}, {Key: "TEST_SECRETS_KEY"}, {Key: "OTHER"}, {Key: "e2e_test_lower"}},
+			allowedPrefixes: []string{"E2E_TEST_", "TEST_SECRETS_"},
+			invalid:         []string{"OTHER", "e2e_test_lower"},
+		},
+		{
+			name:            "Allowed Prefix Does Not Override Deny-List",
+			vars:            []secretVar{{Key: "GITHUB_TOKEN"}},
+			allowedPrefixes: []string{"GITHUB_"},
+			invalid:         []string{"GITHUB_TOKEN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvNames(tt.vars, tt.allowedPrefixes)
+			var invalid []string
+			if err != nil {
+				for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var nameErr *EnvNameError
+					if !errors.As(e, &nameErr) {
+						t.Fatalf("validateEnvNames() error = %v, want *EnvNameError", e)
+					}
+					invalid = append(invalid, nameErr.Name)
+				}
+			}
+			if !reflect.DeepEqual(invalid, tt.invalid) {
+				t.Errorf("validateEnvNames() invalid names = %q, want %q", invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestEnvNameErrorLine(t *testing.T) {
+	err := validateEnvNames([]secretVar{{Key: "OK"}, {Key: "PATH", Line: 7}}, nil)
+	want := `invalid environment variable name "PATH" in line: 7. Setting it could change the behaviour of later steps.`
+	if err == nil || err.Error() != want {
+		t.Errorf("validateEnvNames() error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported secrets formats.
+const (
+	formatAuto   = "auto"
+	formatDotenv = "dotenv"
+	formatJSON   = "json"
+	formatYAML   = "yaml"
+	formatTOML   = "toml"
+)
+
+// secretVar is a single variable to mask and export.
+type secretVar struct {
+	Key   string
+	Value string
+	Line  int // Line where the variable is defined, or 0 if unknown
+}
+
+// parseSecrets parses data in the given format into the variables to export. Nested keys
+// of JSON, YAML and TOML documents are flattened into variable names joined by separator.
+// formatAuto detects JSON by a leading { and otherwise tries dotenv, TOML and YAML in turn.
+func parseSecrets(data string, format string, separator string, lookupEnv func(string) (string, bool)) ([]secretVar, error) {
+	switch format {
+	case formatDotenv:
+		return parseDotenvSecrets(data, lookupEnv)
+	case formatJSON:
+		return parseJSONSecrets(data, separator)
+	case formatYAML:
+		return parseYAMLSecrets(data, separator)
+	case formatTOML:
+		return parseTOMLSecrets(data, separator)
+	case formatAuto:
+		if strings.HasPrefix(strings.TrimSpace(data), "{") {
+			return parseJSONSecrets(data, separator)
+		}
+		vars, dotenvErr := parseDotenvSecrets(data, lookupEnv)
+		var parseErr *DotenvParseError
+		if dotenvErr == nil || !errors.As(dotenvErr, &parseErr) {
+			return vars, dotenvErr
+		}
+		if vars, err := parseTOMLSecrets(data, separator); err == nil {
+			return vars, nil
+		}
+		if vars, err := parseYAMLSecrets(data, separator); err == nil {
+			return vars, nil
+		}
+		return nil, fmt.Errorf("%w. The secrets could not be parsed as TOML or YAML either; pass the format explicitly for a detailed error", dotenvErr)
+	default:
+		return nil, fmt.Errorf("unsupported secrets format %q: must be one of %s, %s, %s, %s or %s", format, formatAuto, formatDotenv, formatJSON, formatYAML, formatTOML)
+	}
+}
+
+func parseDotenvSecrets(data string, lookupEnv func(string) (string, bool)) ([]secretVar, error) {
+	entries, err := parseDotenv(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateDotenv(entries, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	// A later assignment of the same KEY replaces the earlier one.
+	var vars []secretVar
+	index := make(map[string]int)
+	for _, entry := range entries {
+		v := secretVar{Key: entry.Key, Value: entry.Value, Line: entry.Line}
+		if i, ok := index[entry.Key]; ok {
+			vars[i] = v
+			continue
+		}
+		index[entry.Key] = len(vars)
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+func parseJSONSecrets(data string, separator string) ([]secretVar, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		// Syntax errors quote the offending character, which may be part of a secret.
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := strings.Count(data[:syntaxErr.Offset], "\n") + 1
+			return nil, fmt.Errorf("could not parse JSON secrets: invalid syntax in line: %d", line)
+		}
+		return nil, fmt.Errorf("could not parse JSON secrets: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("could not parse JSON secrets: unexpected data after the top-level object")
+	}
+	f := newFlattener(separator)
+	f.flattenValue(nil, doc)
+	return f.result()
+}
+
+func parseTOMLSecrets(data string, separator string) ([]secretVar, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(data, &doc); err != nil {
+		// The parser's message quotes the offending input, which may be part of a secret,
+		// so only its position and the last key are reported.
+		var parseErr toml.ParseError
+		if !errors.As(err, &parseErr) {
+			return nil, errors.New("could not parse TOML secrets")
+		}
+		if parseErr.LastKey == "" {
+			return nil, fmt.Errorf("could not parse TOML secrets: invalid syntax in line: %d", parseErr.Position.Line)
+		}
+		return nil, fmt.Errorf("could not parse TOML secrets: invalid syntax in line: %d at key %q", parseErr.Position.Line, parseErr.LastKey)
+	}
+	f := newFlattener(separator)
+	f.flattenValue(nil, doc)
+	return f.result()
+}
+
+func parseYAMLSecrets(data string, separator string) ([]secretVar, error) {
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not parse YAML secrets: %w", err)
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("could not parse YAML secrets: the document must be a mapping")
+	}
+	f := newFlattener(separator)
+	if err := f.flattenYAML(nil, doc.Content[0]); err != nil {
+		return nil, err
+	}
+	return f.result()
+}
+
+// flattener turns nested documents into variables named after the path to each leaf value.
+type flattener struct {
+	separator string
+	vars      []secretVar
+	paths     map[string]string // variable name to the document path it was created from
+	err       error
+}
+
+func newFlattener(separator string) *flattener {
+	return &flattener{separator: separator, paths: make(map[string]string)}
+}
+
+func (f *flattener) result() ([]secretVar, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.vars, nil
+}
+
+// add records a leaf value. The variable name is the upper-cased path joined by the
+// separator, with characters other than letters, digits and underscores replaced by _.
+func (f *flattener) add(path []string, value string, line int) {
+	if f.err != nil {
+		return
+	}
+	name := envName(strings.Join(path, f.separator))
+	docPath := strings.Join(path, ".")
+	if other, ok := f.paths[name]; ok {
+		f.err = fmt.Errorf("could not parse secrets: %q and %q both map to the variable %s", other, docPath, name)
+		return
+	}
+	f.paths[name] = docPath
+	f.vars = append(f.vars, secretVar{Key: name, Value: value, Line: line})
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func (f *flattener) flattenValue(path []string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f.flattenValue(appendPath(path, key), v[key])
+		}
+	case []interface{}:
+		for i, item := range v {
+			f.flattenValue(appendPath(path, strconv.Itoa(i)), item)
+		}
+	case []map[string]interface{}:
+		for i, item := range v {
+			f.flattenValue(appendPath(path, strconv.Itoa(i)), item)
+		}
+	case nil:
+		f.add(path, "", 0)
+	case string:
+		f.add(path, v, 0)
+	case float64:
+		f.add(path, strconv.FormatFloat(v, 'g', -1, 64), 0)
+	case time.Time:
+		f.add(path, v.Format(time.RFC3339Nano), 0)
+	default:
+		f.add(path, fmt.Sprint(v), 0)
+	}
+}
+
+func (f *flattener) flattenYAML(path []string, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode || key.Value == "<<" {
+				return fmt.Errorf("could not parse YAML secrets: unsupported key in line: %d", key.Line)
+			}
+			if err := f.flattenYAML(appendPath(path, key.Value), value); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := f.flattenYAML(appendPath(path, strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return f.flattenYAML(path, node.Alias)
+	case yaml.ScalarNode:
+		value := node.Value
+		if node.Tag == "!!null" {
+			value = ""
+		}
+		f.add(path, value, node.Line)
+	}
+	return nil
+}
+
+// appendPath returns a copy of path with key appended, so sibling paths never share storage.
+func appendPath(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		expected []secretVar
+	}{
+		{
+			name:   "Dotenv",
+			format: formatDotenv,
+			data: `KEY1=VALUE1
+KEY2=VALUE2
+KEY1="VALUE3"`,
+			expected: []secretVar{{Key: "KEY1", Value: "VALUE3", Line: 3}, {Key: "KEY2", Value: "VALUE2", Line: 2}},
+		},
+		{
+			name:   "JSON",
+			format: formatJSON,
+			data: `{
+  "chains": [{"rpc_url": "wss://rpc", "chain_id": 1337}],
+  "enabled": true,
+  "ratio": 0.5,
+  "empty": null,
+  "e2e-test": {"private.key": "0xabc"}
+}`,
+			expected: []secretVar{
+				{Key: "CHAINS__0__CHAIN_ID", Value: "1337"},
+				{Key: "CHAINS__0__RPC_URL", Value: "wss://rpc"},
+				{Key: "E2E_TEST__PRIVATE_KEY", Value: "0xabc"},
+				{Key: "EMPTY", Value: ""},
+				{Key: "ENABLED", Value: "true"},
+				{Key: "RATIO", Value: "0.5"},
+			},
+		},
+		{
+			name:   "YAML",
+			format: formatYAML,
+			data: `chains:
+  - rpc_url: wss://rpc
+    chain_id: 1337
+anchor: &url https://example.com
+alias: *url
+multiline: |
+  line1
+  line2
+empty:
+`,
+			expected: []secretVar{
+				{Key: "CHAINS__0__RPC_URL", Value: "wss://rpc", Line: 2},
+				{Key: "CHAINS__0__CHAIN_ID", Value: "1337", Line: 3},
+				{Key: "ANCHOR", Value: "https://example.com", Line: 4},
+				{Key: "ALIAS", Value: "https://example.com", Line: 4},
+				{Key: "MULTILINE", Value: "line1\nline2\n", Line: 6},
+				{Key: "EMPTY", Value: "", Line: 9},
+			},
+		},
+		{
+			name:   "TOML",
+			format: formatTOML,
+			data: `enabled = true
+
+[[Chains]]
+rpc_url = "wss://rpc"
+chain_id = 1337
+
+[Network]
+selected_networks = ["simulated", "sepolia"]
+timeout = 1.5
+`,
+			expected: []secretVar{
+				{Key: "CHAINS__0__CHAIN_ID", Value: "1337"},
+				{Key: "CHAINS__0__RPC_URL", Value: "wss://rpc"},
+				{Key: "NETWORK__SELECTED_NETWORKS__0", Value: "simulated"},
+				{Key: "NETWORK__SELECTED_NETWORKS__1", Value: "sepolia"},
+				{Key: "NETWORK__TIMEOUT", Value: "1.5"},
+				{Key: "ENABLED", Value: "true"},
+			},
+		},
+		{
+			name:     "Auto JSON",
+			format:   formatAuto,
+			data:     ` {"key": "value"}`,
+			expected: []secretVar{{Key: "KEY", Value: "value"}},
+		},
+		{
+			name:     "Auto Dotenv",
+			format:   formatAuto,
+			data:     "KEY=value",
+			expected: []secretVar{{Key: "KEY", Value: "value", Line: 1}},
+		},
+		{
+			name:     "Auto TOML",
+			format:   formatAuto,
+			data:     "[section]\nkey = \"value\"",
+			expected: []secretVar{{Key: "SECTION__KEY", Value: "value"}},
+		},
+		{
+			name:     "Auto YAML",
+			format:   formatAuto,
+			data:     "section:\n  key: value",
+			expected: []secretVar{{Key: "SECTION__KEY", Value: "value", Line: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseSecrets(tt.data, tt.format, "__", nil)
+			if err != nil {
+				t.Fatalf("parseSecrets(%q) error = %v, want nil", tt.data, err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseSecrets(%q) = %v, want %v", tt.data, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSecretsSeparator(t *testing.T) {
+	result, err := parseSecrets(`{"a": {"b": ["c"]}}`, formatJSON, "_", nil)
+	if err != nil {
+		t.Fatalf("parseSecrets() error = %v, want nil", err)
+	}
+	expected := []secretVar{{Key: "A_B_0", Value: "c"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("parseSecrets() = %v, want %v", result, expected)
+	}
+}
+
+func TestParseSecretsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		err    string
+	}{
+		{name: "Unsupported Format", format: "xml", data: "<a/>", err: `unsupported secrets format "xml"`},
+		{name: "Invalid JSON", format: formatJSON, data: `{"key": }`, err: "could not parse JSON secrets"},
+		{name: "JSON Array", format: formatJSON, data: `["value"]`, err: "could not parse JSON secrets"},
+		{name: "Trailing JSON", format: formatJSON, data: `{"a": "b"} {}`, err: "unexpected data after the top-level object"},
+		{name: "Invalid TOML", format: formatTOML, data: "key = ", err: "could not parse TOML secrets"},
+		{name: "Invalid TOML Position", format: formatTOML, data: "a = \"b\"\nkey = s3cretvalue", err: `could not parse TOML secrets: invalid syntax in line: 2 at key "key"`},
+		{name: "Invalid JSON Position", format: formatJSON, data: "{\n\"key\": s3cretvalue}", err: "could not parse JSON secrets: invalid syntax in line: 2"},
+		{name: "YAML Scalar", format: formatYAML, data: "value", err: "the document must be a mapping"},
+		{name: "Key Collision", format: formatJSON, data: `{"a_b": "1", "A-B": "2"}`, err: `"A-B" and "a_b" both map to the variable A_B`},
+		{name: "Auto Undetected", format: formatAuto, data: "not a secrets file", err: "could not parse dotenv due to invalid KEY=\"VALUE\" in line: 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSecrets(tt.data, tt.format, "__", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseSecrets(%q) error = %v, want it to contain %q", tt.data, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseSecretsErrorsDoNotLeakValues(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+	}{
+		{format: formatTOML, data: "key = s3cretvalue"},
+		{format: formatTOML, data: "key = 0xZZsecret"},
+		{format: formatTOML, data: "key = \"s3cretvalue"},
+		{format: formatJSON, data: `{"key": s3cretvalue}`},
+	}
+
+	for _, tt := range tests {
+		_, err := parseSecrets(tt.data, tt.format, "__", nil)
+		if err == nil {
+			t.Errorf("parseSecrets(%q) error = nil, want error", tt.data)
+			continue
+		}
+		for _, fragment := range []string{"s3cret", "'s'", `"s"`, "0xZ", "ZZ"} {
+			if strings.Contains(err.Error(), fragment) {
+				t.Errorf("parseSecrets(%q) error = %v, contains %q", tt.data, err, fragment)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// githubEnvDelimiterPrefix follows the heredoc delimiter format used by the GitHub runner itself.
+const githubEnvDelimiterPrefix = "ghadelimiter_"
+
+// addToGithubEnv appends key and value to the GITHUB_ENV file at path using the
+// multiline KEY<<DELIMITER syntax, so that the value is stored verbatim regardless
+// of quotes, dollar signs, backticks or newlines it contains.
+func addToGithubEnv(path, key, value string) error {
+	if path == "" {
+		return errors.New("GITHUB_ENV is not set")
+	}
+	if key == "" || strings.ContainsAny(key, "=\r\n") || strings.Contains(key, "<<") {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	// The runner strips a carriage return right before the closing delimiter together with the line break.
+	if strings.HasSuffix(value, "\r") {
+		return errors.New("value must not end with a carriage return")
+	}
+
+	delimiter, err := githubEnvDelimiter(value)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s<<%s\n%s\n%s\n", key, delimiter, value, delimiter); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// githubEnvDelimiter returns a random heredoc delimiter that does not occur in value.
+func githubEnvDelimiter(value string) (string, error) {
+	b := make([]byte, 16)
+	for i := 0; i < 10; i++ {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		delimiter := githubEnvDelimiterPrefix + hex.EncodeToString(b)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+	return "", errors.New("could not generate a delimiter that does not occur in the value")
+}
@@ -1,3 +1,8 @@
 module github.com/smartcontractkit/chainlink-github-actions/chainlink-testing-framework/run-tests/mask-testsecrets
 
 go 1.21.3
+
+require (
+	github.com/BurntSushi/toml v1.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
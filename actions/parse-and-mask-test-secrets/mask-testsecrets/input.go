@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// secretsSource describes where the base64-encoded secrets are read from. At most one
+// of its fields may be set.
+type secretsSource struct {
+	Stdin bool   // Read from standard input
+	Env   string // Read from the environment variable with this name
+	File  string // Read from the file at this path
+	Arg   string // Read from the command line, which exposes the secrets in the process table
+}
+
+// readEncodedSecrets returns the base64-encoded secrets from the configured source, with
+// surrounding whitespace removed.
+func readEncodedSecrets(src secretsSource, stdin io.Reader, lookupEnv func(string) (string, bool)) (string, error) {
+	var sources []string
+	if src.Stdin {
+		sources = append(sources, "stdin")
+	}
+	if src.Env != "" {
+		sources = append(sources, "environment variable")
+	}
+	if src.File != "" {
+		sources = append(sources, "file")
+	}
+	if src.Arg != "" {
+		sources = append(sources, "argument")
+	}
+	if len(sources) != 1 {
+		return "", fmt.Errorf("exactly one source of test secrets is required, got %d: %s", len(sources), strings.Join(sources, ", "))
+	}
+
+	var encoded string
+	switch {
+	case src.Stdin:
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("could not read test secrets from stdin: %w", err)
+		}
+		encoded = string(b)
+	case src.Env != "":
+		value, ok := lookupEnv(src.Env)
+		if !ok {
+			return "", fmt.Errorf("could not read test secrets: environment variable %s is not set", src.Env)
+		}
+		encoded = value
+	case src.File != "":
+		b, err := os.ReadFile(src.File)
+		if err != nil {
+			return "", fmt.Errorf("could not read test secrets: %w", err)
+		}
+		encoded = string(b)
+	default:
+		encoded = src.Arg
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return "", errors.New("could not read test secrets: the input is empty")
+	}
+	return encoded, nil
+}
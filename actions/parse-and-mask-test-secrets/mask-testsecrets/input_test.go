@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadEncodedSecrets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secrets")
+	if err := os.WriteFile(file, []byte("ZnJvbS1maWxl\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"ENCODED": " ZnJvbS1lbnY= \n", "EMPTY": ""}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		name     string
+		src      secretsSource
+		stdin    string
+		expected string
+		err      string
+	}{
+		{name: "Stdin", src: secretsSource{Stdin: true}, stdin: "ZnJvbS1zdGRpbg==\n", expected: "ZnJvbS1zdGRpbg=="},
+		{name: "Env", src: secretsSource{Env: "ENCODED"}, expected: "ZnJvbS1lbnY="},
+		{name: "File", src: secretsSource{File: file}, expected: "ZnJvbS1maWxl"},
+		{name: "Argument", src: secretsSource{Arg: "ZnJvbS1hcmc="}, expected: "ZnJvbS1hcmc="},
+		{name: "No Source", src: secretsSource{}, err: "exactly one source of test secrets is required, got 0"},
+		{name: "Several Sources", src: secretsSource{Stdin: true, File: file}, err: "exactly one source of test secrets is required, got 2: stdin, file"},
+		{name: "Unset Env", src: secretsSource{Env: "UNSET"}, err: "environment variable UNSET is not set"},
+		{name: "Empty Env", src: secretsSource{Env: "EMPTY"}, err: "the input is empty"},
+		{name: "Missing File", src: secretsSource{File: filepath.Join(t.TempDir(), "missing")}, err: "no such file or directory"},
+		{name: "Empty Stdin", src: secretsSource{Stdin: true}, stdin: "\n", err: "the input is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := readEncodedSecrets(tt.src, strings.NewReader(tt.stdin), lookupEnv)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("readEncodedSecrets() error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEncodedSecrets() error = %v, want nil", err)
+			}
+			if result != tt.expected {
+				t.Errorf("readEncodedSecrets() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
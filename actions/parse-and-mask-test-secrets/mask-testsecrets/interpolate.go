@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DotenvReferenceError reports a ${NAME} reference that could not be resolved.
+type DotenvReferenceError struct {
+	Line   int    // Line of the assignment containing the reference
+	Name   string // Name of the referenced variable
+	Reason string
+}
+
+// Error implements the error interface for DotenvReferenceError.
+func (e *DotenvReferenceError) Error() string {
+	return fmt.Sprintf("could not resolve ${%s} in line: %d. %s", e.Name, e.Line, e.Reason)
+}
+
+// interpolateDotenv replaces the references in every entry's VALUE, in the order the
+// entries appear. A reference resolves to the latest earlier assignment of that name,
+// then to lookupEnv if it is not nil, and finally to its default if it has one. The
+// default is also used when the resolved value is empty. All unresolved references
+// are reported together.
+func interpolateDotenv(entries []dotenvEntry, lookupEnv func(string) (string, bool)) error {
+	resolved := make(map[string]string)
+	var errs []error
+	for i := range entries {
+		entry := &entries[i]
+		var b strings.Builder
+		for _, part := range entry.Parts {
+			if part.Ref == nil {
+				b.WriteString(part.Literal)
+				continue
+			}
+			value, ok := resolved[part.Ref.Name]
+			if !ok && lookupEnv != nil {
+				value, ok = lookupEnv(part.Ref.Name)
+			}
+			if part.Ref.HasDefault && value == "" {
+				value, ok = part.Ref.Default, true
+			}
+			if !ok {
+				errs = append(errs, unresolvedReference(entries, i, part.Ref.Name))
+				continue
+			}
+			b.WriteString(value)
+		}
+		entry.Value = b.String()
+		resolved[entry.Key] = entry.Value
+	}
+	return errors.Join(errs...)
+}
+
+// unresolvedReference explains why entries[i] could not resolve the reference to name.
+func unresolvedReference(entries []dotenvEntry, i int, name string) *DotenvReferenceError {
+	err := &DotenvReferenceError{Line: entries[i].Line, Name: name, Reason: "Variable is not defined."}
+	if path := referencePath(entries, name, entries[i].Key); path != nil {
+		err.Reason = fmt.Sprintf("Reference cycle: %s.", strings.Join(append([]string{entries[i].Key}, path...), " -> "))
+		return err
+	}
+	for _, later := range entries[i+1:] {
+		if later.Key == name {
+			err.Reason = fmt.Sprintf("Variable is only defined later, in line: %d. References resolve against earlier lines only.", later.Line)
+			break
+		}
+	}
+	return err
+}
+
+// referencePath returns the chain of references leading from variable from to variable
+// to, including both ends, or nil if there is none.
+func referencePath(entries []dotenvEntry, from, to string) []string {
+	refs := make(map[string][]string)
+	for _, entry := range entries {
+		for _, part := range entry.Parts {
+			if part.Ref != nil {
+				refs[entry.Key] = append(refs[entry.Key], part.Ref.Name)
+			}
+		}
+	}
+
+	visited := make(map[string]bool)
+	var walk func(name string) []string
+	walk = func(name string) []string {
+		if name == to {
+			return []string{name}
+		}
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		for _, next := range refs[name] {
+			if path := walk(next); path != nil {
+				return append([]string{name}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// allowedEnvLookup returns a lookup of process environment variables restricted to the
+// comma-separated names in allowList, or nil if allowList is empty.
+func allowedEnvLookup(allowList string) func(string) (string, bool) {
+	allowed := make(map[string]bool)
+	for _, name := range splitList(allowList) {
+		allowed[name] = true
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return func(name string) (string, bool) {
+		if !allowed[name] {
+			return "", false
+		}
+		return os.LookupEnv(name)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenvSecretsInterpolation(t *testing.T) {
+	env := map[string]string{"ALLOWED": "from-env", "EMPTY_ENV": ""}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		name     string
+		envStr   string
+		expected map[string]string
+		errs     []*DotenvReferenceError
+	}{
+		{
+			name: "Earlier Keys",
+			envStr: `HOST=db.example.com
+USER=admin
+URL=postgres://${USER}@${HOST}:5432
+QUOTED="https://${HOST}/api"`,
+			expected: map[string]string{
+				"HOST":   "db.example.com",
+				"USER":   "admin",
+				"URL":    "postgres://admin@db.example.com:5432",
+				"QUOTED": "https://db.example.com/api",
+			},
+		},
+		{
+			name: "Latest Earlier Assignment",
+			envStr: `PATH_PART=a
+PATH_PART=${PATH_PART}:b
+FIRST=${PATH_PART}
+PATH_PART=c`,
+			expected: map[string]string{"PATH_PART": "c", "FIRST": "a:b"},
+		},
+		{
+			name: "Defaults",
+			envStr: `EMPTY=
+SET=value
+KEY1=${MISSING:-fallback}
+KEY2=${EMPTY:-fallback}
+KEY3=${SET:-fallback}
+KEY4="${MISSING:-two words}"
+KEY5=${EMPTY_ENV:-fallback}`,
+			expected: map[string]string{
+				"EMPTY": "",
+				"SET":   "value",
+				"KEY1":  "fallback",
+				"KEY2":  "fallback",
+				"KEY3":  "value",
+				"KEY4":  "two words",
+				"KEY5":  "fallback",
+			},
+		},
+		{
+			name: "Process Environment",
+			envStr: `ALLOWED=from-bundle
+KEY1=${ALLOWED}
+KEY2=${EMPTY_ENV}`,
+			expected: map[string]string{"ALLOWED": "from-bundle", "KEY1": "from-bundle", "KEY2": ""},
+		},
+		{
+			name: "Literal Dollar Signs",
+			envStr: `KEY=secret
+KEY1='${KEY}'
+KEY2="\${KEY}"
+KEY3=$KEY
+KEY4=$`,
+			expected: map[string]string{"KEY": "secret", "KEY1": "${KEY}", "KEY2": "${KEY}", "KEY3": "$KEY", "KEY4": "$"},
+		},
+		{
+			name: "Undefined References",
+			envStr: `KEY1=${UNDEFINED}
+KEY2=ok
+KEY3="${NOT_ALLOWED} and ${ALSO_UNDEFINED}"`,
+			errs: []*DotenvReferenceError{
+				{Line: 1, Name: "UNDEFINED", Reason: "Variable is not defined."},
+				{Line: 3, Name: "NOT_ALLOWED", Reason: "Variable is not defined."},
+				{Line: 3, Name: "ALSO_UNDEFINED", Reason: "Variable is not defined."},
+			},
+		},
+		{
+			name: "Forward Reference",
+			envStr: `URL=https://${HOST}
+
+HOST=example.com`,
+			errs: []*DotenvReferenceError{
+				{Line: 1, Name: "HOST", Reason: "Variable is only defined later, in line: 3. References resolve against earlier lines only."},
+			},
+		},
+		{
+			name: "Cycles",
+			envStr: `A=${B}
+B=${C}
+C=${A}
+SELF=${SELF}`,
+			errs: []*DotenvReferenceError{
+				{Line: 1, Name: "B", Reason: "Reference cycle: A -> B -> C -> A."},
+				{Line: 4, Name: "SELF", Reason: "Reference cycle: SELF -> SELF."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := dotenvValues(tt.envStr, lookupEnv)
+			if len(tt.errs) == 0 && err != nil {
+				t.Errorf("parseDotenvSecrets(%q) error = %v, want nil", tt.envStr, err)
+			}
+			if len(tt.errs) > 0 && err == nil {
+				t.Fatalf("parseDotenvSecrets(%q) error = nil, want %v", tt.envStr, tt.errs)
+			}
+			for _, want := range tt.errs {
+				var found bool
+				for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var got *DotenvReferenceError
+					if errors.As(e, &got) && *got == *want {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("parseDotenvSecrets(%q) error = %v, want it to contain %v", tt.envStr, err, want)
+				}
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseDotenvSecrets(%q) = %v, want %v", tt.envStr, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDotenvSecretsInvalidReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		envStr string
+		err    error
+	}{
+		{name: "Unterminated", envStr: "KEY1=a\nKEY2=${HOST", err: &DotenvParseError{Line: 2}},
+		{name: "Empty Name", envStr: "KEY=${}", err: &DotenvParseError{Line: 1}},
+		{name: "Empty Name with Default", envStr: `KEY="${:-x}"`, err: &DotenvParseError{Line: 1}},
+		{name: "Invalid Name", envStr: `KEY="${A B}"`, err: &DotenvParseError{Line: 1}},
+		{name: "Nested Reference in Default", envStr: "C=c\nA=${B:-${C}}", err: &DotenvParseError{Line: 2}},
+		{name: "Nested Reference in Quoted Default", envStr: `A="${B:-x${C}y}"`, err: &DotenvParseError{Line: 1}},
+		{name: "Brace in Default", envStr: `A="${B:-a{b}"`, err: &DotenvParseError{Line: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dotenvValues(tt.envStr, nil)
+			if err == nil || err.Error() != tt.err.Error() {
+				t.Errorf("parseDotenvSecrets(%q) error = %v, want %v", tt.envStr, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAllowedEnvLookup(t *testing.T) {
+	t.Setenv("MASK_TESTSECRETS_ALLOWED", "allowed")
+	t.Setenv("MASK_TESTSECRETS_DENIED", "denied")
+
+	if lookup := allowedEnvLookup(" , "); lookup != nil {
+		t.Errorf("allowedEnvLookup(%q) = non-nil, want nil", " , ")
+	}
+
+	lookup := allowedEnvLookup("MASK_TESTSECRETS_ALLOWED, MASK_TESTSECRETS_UNSET")
+	if value, ok := lookup("MASK_TESTSECRETS_ALLOWED"); !ok || value != "allowed" {
+		t.Errorf("lookup(MASK_TESTSECRETS_ALLOWED) = %q, %v, want %q, true", value, ok, "allowed")
+	}
+	if _, ok := lookup("MASK_TESTSECRETS_UNSET"); ok {
+		t.Errorf("lookup(MASK_TESTSECRETS_UNSET) ok = true, want false")
+	}
+	if _, ok := lookup("MASK_TESTSECRETS_DENIED"); ok {
+		t.Errorf("lookup(MASK_TESTSECRETS_DENIED) ok = true, want false")
+	}
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/base64"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"regexp"
 	"strings"
+	"unicode"
 )
 
 type DotenvParseError struct {
@@ -15,95 +17,409 @@ type DotenvParseError struct {
 
 // Error implements the error interface for DotenvParseError.
 func (e *DotenvParseError) Error() string {
-	return fmt.Sprintf("could not parse dotenv due to invalid KEY=\"VALUE\" in line: %d. Only KEY=VALUE or KEY=\"VALUE\" are supported. To include special characters such as an apostrophe in the VALUE, enclose the VALUE in double quotes (e.g., KEY=\"'VALUE'\")", e.Line)
+	return fmt.Sprintf("could not parse dotenv due to invalid KEY=\"VALUE\" in line: %d. Only KEY=VALUE, KEY=\"VALUE\" or KEY='VALUE' are supported. To include special characters such as spaces or quotes in the VALUE, enclose the VALUE in quotes (e.g., KEY=\"'VALUE'\")", e.Line)
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <base64_dot_env_string>")
+	var src secretsSource
+	flag.BoolVar(&src.Stdin, "stdin", false, "read the base64-encoded secrets from standard input")
+	flag.StringVar(&src.Env, "env", "", "read the base64-encoded secrets from the environment variable with this name")
+	flag.StringVar(&src.File, "file", "", "read the base64-encoded secrets from the file at this path")
+	format := flag.String("format", formatAuto, "format of the decoded secrets: auto, dotenv, json, yaml or toml")
+	separator := flag.String("separator", "__", "separator joining nested JSON, YAML and TOML keys into variable names")
+	allowEnv := flag.String("allow-env", "", "comma-separated names of process environment variables that ${NAME} references may resolve to")
+	allowPrefix := flag.String("allow-prefix", "", "comma-separated prefixes that every exported variable name must start with")
+	manifestPath := flag.String("manifest", "", "path to a YAML manifest describing the required and typed keys")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: go run . [flags] (-stdin | -env NAME | -file PATH)")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 {
+		// Deprecated: arguments are visible to every process on the runner.
+		fmt.Println("::warning::Passing test secrets as a command line argument is deprecated because it exposes them in the process table. Use -stdin, -env or -file instead.")
+		src.Arg = flag.Arg(0)
+	}
+	if !src.Stdin && src.Env == "" && src.File == "" && src.Arg == "" {
+		flag.Usage()
 		return
 	}
 
-	decodedBytes, err := base64.StdEncoding.DecodeString(os.Args[1])
+	encoded, err := readEncodedSecrets(src, os.Stdin, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Could not decode provided base64 string")
 		os.Exit(1)
 	}
 
-	envVars, err := parseEnvVars(string(decodedBytes))
+	secretVars, err := parseSecrets(string(decodedBytes), *format, *separator, allowedEnvLookup(*allowEnv))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	// Report every problem with the keys at once, before anything is exported.
+	errs := []error{validateEnvNames(secretVars, splitList(*allowPrefix))}
+	if *manifestPath != "" {
+		m, err := loadManifest(*manifestPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		errs = append(errs, validateManifest(m, secretVars))
+	}
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, v := range secretVars {
+		mustMaskSecret(v.Key, v.Value)
+		mustAddToGithubEnv(v.Key, v.Value)
+	}
+}
+
+// dotenvEntry is a single KEY=VALUE assignment from a dotenv file.
+type dotenvEntry struct {
+	Key   string
+	Value string
+	Line  int  // Line where the assignment starts
+	Quote byte // Quote character enclosing the VALUE, or 0 if the VALUE is unquoted
+	Parts []dotenvValuePart
+}
 
-	for key, value := range envVars {
-		mustMaskSecret(key, value)
-		mustAddToGithubEnv(key, value)
+// dotenvValuePart is either literal text or a ${NAME} reference within a VALUE.
+type dotenvValuePart struct {
+	Literal string
+	Ref     *dotenvReference
+}
+
+// dotenvReference is a ${NAME} or ${NAME:-DEFAULT} reference to another variable.
+type dotenvReference struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+func (r *dotenvReference) String() string {
+	if r.HasDefault {
+		return "${" + r.Name + ":-" + r.Default + "}"
 	}
+	return "${" + r.Name + "}"
 }
 
-func parseEnvVars(envStr string) (map[string]string, error) {
-	envVars := make(map[string]string)
-	// Regular expression to match configurations in the form of KEY=VALUE or KEY="VALUE".
-	// To include special characters such as an apostrophe in the VALUE, enclose the VALUE in double quotes (e.g., KEY="'VALUE'").
-	// Restrictions:
-	// - KEY must not be enclosed in any type of quotes.
-	// - VALUE can be unquoted or enclosed in double quotes, but not single quotes.
-	re := regexp.MustCompile(`^[^" '=]+=".*"$|^[^" '=]+=[^" ']+$`)
+// dotenvValueBuilder collects the parts of a VALUE while it is scanned.
+type dotenvValueBuilder struct {
+	parts   []dotenvValuePart
+	literal strings.Builder
+}
 
-	lines := strings.Split(envStr, "\n")
-	for i, line := range lines {
-		line = strings.TrimSpace(line) // Trim space from start and end
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue // Skip empty lines and comments
+func (b *dotenvValueBuilder) writeByte(c byte) {
+	b.literal.WriteByte(c)
+}
+
+func (b *dotenvValueBuilder) writeRef(ref *dotenvReference) {
+	b.flush()
+	b.parts = append(b.parts, dotenvValuePart{Ref: ref})
+}
+
+func (b *dotenvValueBuilder) flush() {
+	if b.literal.Len() > 0 {
+		b.parts = append(b.parts, dotenvValuePart{Literal: b.literal.String()})
+		b.literal.Reset()
+	}
+}
+
+// value returns the parts and the VALUE as written, with references left unresolved.
+func (b *dotenvValueBuilder) value() ([]dotenvValuePart, string) {
+	b.flush()
+	var raw strings.Builder
+	for _, part := range b.parts {
+		if part.Ref != nil {
+			raw.WriteString(part.Ref.String())
+		} else {
+			raw.WriteString(part.Literal)
+		}
+	}
+	return b.parts, raw.String()
+}
+
+// parseDotenv parses envStr into its assignments in the order they appear.
+// Supported syntax, compatible with common dotenv implementations:
+//   - Lines may be prefixed with export. Empty lines and lines starting with # are skipped.
+//   - KEY must not be enclosed in any type of quotes and must not contain whitespace.
+//   - An unquoted VALUE must not contain quotes or whitespace and ends at the end of the
+//     line or at a # comment preceded by whitespace.
+//   - A single-quoted VALUE is taken literally and may span multiple lines.
+//   - A double-quoted VALUE may span multiple lines and supports the escapes \n, \", \\
+//     and \$. Any other backslash is kept as is.
+//   - A quoted VALUE may be followed by whitespace and a # comment.
+//   - An unquoted or double-quoted VALUE may reference other variables as ${NAME} or
+//     ${NAME:-DEFAULT}. DEFAULT is literal text without $, { or }. Use \$ in a
+//     double-quoted VALUE for a literal $.
+func parseDotenv(envStr string) ([]dotenvEntry, error) {
+	s := &dotenvScanner{src: strings.ReplaceAll(envStr, "\r\n", "\n")}
+	var entries []dotenvEntry
+	for {
+		s.skipBlank()
+		if s.eof() {
+			return entries, nil
+		}
+		if s.peek() == '#' {
+			s.skipLine()
+			continue
 		}
-		if strings.HasPrefix(line, "export ") {
-			line = strings.TrimSpace(strings.TrimPrefix(line, "export"))
+
+		line := s.line()
+		entry, ok := s.scanEntry()
+		if !ok {
+			return nil, &DotenvParseError{Line: line}
 		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+}
+
+type dotenvScanner struct {
+	src string
+	pos int
+}
+
+func (s *dotenvScanner) eof() bool {
+	return s.pos >= len(s.src)
+}
+
+func (s *dotenvScanner) peek() byte {
+	return s.src[s.pos]
+}
+
+// line returns the 1-based line number of the current position.
+func (s *dotenvScanner) line() int {
+	return strings.Count(s.src[:s.pos], "\n") + 1
+}
+
+// restOfLine returns the text from the current position up to the end of the line.
+func (s *dotenvScanner) restOfLine() string {
+	if i := strings.IndexByte(s.src[s.pos:], '\n'); i >= 0 {
+		return s.src[s.pos : s.pos+i]
+	}
+	return s.src[s.pos:]
+}
+
+func (s *dotenvScanner) skipSpaces() {
+	for !s.eof() && (s.peek() == ' ' || s.peek() == '\t') {
+		s.pos++
+	}
+}
+
+// skipBlank skips whitespace including line breaks.
+func (s *dotenvScanner) skipBlank() {
+	for !s.eof() && strings.IndexByte(" \t\r\n", s.peek()) >= 0 {
+		s.pos++
+	}
+}
+
+// skipLine moves past the end of the current line.
+func (s *dotenvScanner) skipLine() {
+	s.pos += len(s.restOfLine())
+	if !s.eof() {
+		s.pos++
+	}
+}
+
+func (s *dotenvScanner) scanEntry() (dotenvEntry, bool) {
+	if rest := s.restOfLine(); strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+		s.pos += len("export")
+		s.skipSpaces()
+	}
 
-		// Validate the line with regex before processing
-		if re.MatchString(line) {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := removeSurroundingQuotes(strings.TrimSpace(parts[1]))
-				envVars[key] = value
+	eq := strings.IndexByte(s.restOfLine(), '=')
+	if eq <= 0 {
+		return dotenvEntry{}, false
+	}
+	key := s.src[s.pos : s.pos+eq]
+	if strings.ContainsAny(key, "\"' \t") {
+		return dotenvEntry{}, false
+	}
+	s.pos += eq + 1
+
+	entry := dotenvEntry{Key: key}
+	var b dotenvValueBuilder
+	var ok bool
+	switch {
+	case s.eof():
+		ok = true
+	case s.peek() == '"':
+		entry.Quote = '"'
+		ok = s.scanDoubleQuoted(&b)
+	case s.peek() == '\'':
+		entry.Quote = '\''
+		ok = s.scanSingleQuoted(&b)
+	default:
+		ok = s.scanUnquoted(&b)
+	}
+	entry.Parts, entry.Value = b.value()
+	return entry, ok
+}
+
+func (s *dotenvScanner) scanUnquoted(b *dotenvValueBuilder) bool {
+	value := s.restOfLine()
+	s.skipLine()
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\"' \t") {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if strings.HasPrefix(value[i:], "${") {
+			ref, n, ok := scanReference(value[i:])
+			if !ok {
+				return false
 			}
-		} else {
-			return nil, &DotenvParseError{Line: i + 1}
+			b.writeRef(ref)
+			i += n - 1
+			continue
 		}
+		b.writeByte(value[i])
 	}
-	return envVars, nil
+	return true
 }
 
-func removeSurroundingQuotes(s string) string {
-	if len(s) >= 2 {
-		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-			return s[1 : len(s)-1]
+func (s *dotenvScanner) scanSingleQuoted(b *dotenvValueBuilder) bool {
+	s.pos++ // opening quote
+	end := strings.IndexByte(s.src[s.pos:], '\'')
+	if end < 0 {
+		return false
+	}
+	b.literal.WriteString(s.src[s.pos : s.pos+end])
+	s.pos += end + 1
+	return s.scanTrailer()
+}
+
+func (s *dotenvScanner) scanDoubleQuoted(b *dotenvValueBuilder) bool {
+	s.pos++ // opening quote
+	for !s.eof() {
+		c := s.peek()
+		switch {
+		case c == '"':
+			s.pos++
+			return s.scanTrailer()
+		case c == '$' && strings.HasPrefix(s.src[s.pos:], "${"):
+			ref, n, ok := scanReference(s.src[s.pos:])
+			if !ok {
+				return false
+			}
+			b.writeRef(ref)
+			s.pos += n
+		case c == '\\' && s.pos+1 < len(s.src):
+			s.pos++
+			switch e := s.peek(); e {
+			case 'n':
+				b.writeByte('\n')
+				s.pos++
+			case '"', '\\', '$':
+				b.writeByte(e)
+				s.pos++
+			default:
+				b.writeByte(c)
+			}
+		default:
+			b.writeByte(c)
+			s.pos++
 		}
 	}
-	return s
+	return false
+}
+
+// scanReference scans a ${NAME} or ${NAME:-DEFAULT} reference at the start of src
+// and returns it together with its length.
+func scanReference(src string) (*dotenvReference, int, bool) {
+	end := strings.IndexByte(src, '}')
+	if end < 0 {
+		return nil, 0, false
+	}
+	body := src[len("${"):end]
+	ref := &dotenvReference{Name: body}
+	if i := strings.Index(body, ":-"); i >= 0 {
+		ref.Name, ref.Default, ref.HasDefault = body[:i], body[i+len(":-"):], true
+	}
+	// DEFAULT is literal text, so nested references such as ${A:-${B}} are rejected
+	// rather than cut off at the first closing brace.
+	if ref.Name == "" || strings.ContainsAny(ref.Name, "\"' \t\n=${:") || strings.ContainsAny(ref.Default, "\n${") {
+		return nil, 0, false
+	}
+	return ref, end + 1, true
+}
+
+// scanTrailer consumes what follows a closing quote, which may only be whitespace and a # comment.
+func (s *dotenvScanner) scanTrailer() bool {
+	s.skipSpaces()
+	if s.eof() || s.peek() == '\n' || s.peek() == '#' {
+		s.skipLine()
+		return true
+	}
+	return false
 }
 
 func mustAddToGithubEnv(key, value string) {
-	command := fmt.Sprintf("echo \"%s=%s\" >> $GITHUB_ENV", key, value)
-	cmd := exec.Command("bash", "-c", command)
-	err := cmd.Run()
+	err := addToGithubEnv(os.Getenv("GITHUB_ENV"), key, value)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to add '%s' to GITHUB_ENV", key)
+		fmt.Fprintf(os.Stderr, "Failed to add '%s' to GITHUB_ENV: %v\n", key, err)
 		os.Exit(1)
 	}
 }
 
 func mustMaskSecret(description string, secret string) {
-	if secret != "" {
-		fmt.Printf("Mask '%s'\n", description)
-		fmt.Printf("::add-mask::%s\n", secret)
-		cmd := exec.Command("bash", "-c", "echo ::add-mask::$0", "_", secret)
-		err := cmd.Run()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to mask secret '%s'", description)
-			os.Exit(1)
+	maskSecret(os.Stdout, description, secret)
+}
+
+// minMaskLineLength is the shortest line of a multiline secret that is masked on its own.
+// Shorter lines, such as the braces of a JSON document, would redact unrelated output.
+const minMaskLineLength = 4
+
+// maskSecret writes ::add-mask:: workflow commands for secret and its derived encodings
+// to w. GitHub reads workflow commands line by line, so every line of a multiline secret
+// is masked on its own, except for lines that are too short or have no letters or digits.
+func maskSecret(w io.Writer, description string, secret string) {
+	if secret == "" {
+		return
+	}
+	fmt.Fprintf(w, "Mask '%s'\n", description)
+	masked := make(map[string]bool)
+	for _, variant := range maskVariants(secret) {
+		lines := strings.Split(variant, "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || masked[line] {
+				continue
+			}
+			if len(lines) > 1 && !isMaskableLine(line) {
+				continue
+			}
+			masked[line] = true
+			fmt.Fprintf(w, "::add-mask::%s\n", escapeCommandData(line))
 		}
 	}
 }
+
+// escapeCommandData escapes s for use as workflow command data. The runner turns %25, %0D
+// and %0A back into %, CR and LF before it registers a mask.
+func escapeCommandData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// isMaskableLine reports whether a line of a multiline secret is specific enough to mask.
+func isMaskableLine(line string) bool {
+	return len(line) >= minMaskLineLength && strings.IndexFunc(line, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
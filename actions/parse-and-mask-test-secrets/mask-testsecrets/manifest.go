@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Value types a manifest can require.
+const (
+	typeString          = "string"
+	typeInt             = "int"
+	typeBool            = "bool"
+	typeURL             = "url"
+	typeDuration        = "duration"
+	typeHex             = "hex"
+	typeEthereumAddress = "ethereum_address"
+	typePrivateKey      = "private_key"
+)
+
+// valueTypes lists the supported value types in the order they are documented.
+var valueTypes = []string{
+	typeString, typeInt, typeBool, typeURL, typeDuration, typeHex, typeEthereumAddress, typePrivateKey,
+}
+
+var (
+	hexRe             = regexp.MustCompile(`^(0x|0X)?[0-9a-fA-F]+$`)
+	ethereumAddressRe = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	hexPrivateKeyRe   = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+)
+
+// manifest describes the keys a secrets bundle is expected to contain.
+type manifest struct {
+	Keys []manifestKey
+}
+
+// manifestKey describes one expected key.
+type manifestKey struct {
+	Name     string
+	Line     int // Line where the key is declared in the manifest
+	Required bool
+	Type     string
+	Regex    *regexp.Regexp
+}
+
+// manifestKeyFields are the fields a key may set in the manifest.
+var manifestKeyFields = map[string]bool{"required": true, "type": true, "regex": true}
+
+// ManifestError reports a problem with the manifest itself or a key that does not match it.
+type ManifestError struct {
+	Key        string
+	Line       int // Line in the secrets, or in the manifest if InManifest is set; 0 if unknown
+	InManifest bool
+	Reason     string
+}
+
+// Error implements the error interface for ManifestError.
+func (e *ManifestError) Error() string {
+	var where string
+	switch {
+	case e.InManifest:
+		where = fmt.Sprintf(" (manifest line: %d)", e.Line)
+	case e.Line > 0:
+		where = fmt.Sprintf(" in line: %d", e.Line)
+	}
+	return fmt.Sprintf("%s%s: %s", e.Key, where, e.Reason)
+}
+
+func loadManifest(path string) (*manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+	return parseManifest(b)
+}
+
+// parseManifest parses a YAML manifest of the form:
+//
+//	keys:
+//	  E2E_TEST_PRIVATE_KEY:
+//	    required: true
+//	    type: private_key
+//	  E2E_TEST_CHAINLINK_IMAGE:
+//	    regex: ^public\.ecr
+//
+// All problems in the manifest are reported together.
+func parseManifest(data []byte) (*manifest, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %w", err)
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("could not parse manifest: the document must be a mapping with a keys field")
+	}
+
+	var keys *yaml.Node
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "keys" {
+			return nil, fmt.Errorf("could not parse manifest: unknown field %q in line: %d", root.Content[i].Value, root.Content[i].Line)
+		}
+		keys = root.Content[i+1]
+	}
+	if keys == nil || keys.Kind != yaml.MappingNode {
+		return nil, errors.New("could not parse manifest: keys must be a mapping of key names")
+	}
+
+	m := &manifest{}
+	var errs []error
+	for i := 0; i+1 < len(keys.Content); i += 2 {
+		key, err := parseManifestKey(keys.Content[i], keys.Content[i+1])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.Keys = append(m.Keys, key)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+func parseManifestKey(name, spec *yaml.Node) (manifestKey, error) {
+	key := manifestKey{Name: name.Value, Line: name.Line, Type: typeString}
+	fail := func(line int, format string, args ...interface{}) (manifestKey, error) {
+		return manifestKey{}, &ManifestError{Key: key.Name, Line: line, InManifest: true, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// A key without settings, such as "KEY:" or "KEY: {}", is an optional string.
+	if spec.Kind == yaml.ScalarNode && spec.Tag == "!!null" {
+		return key, nil
+	}
+	if spec.Kind != yaml.MappingNode {
+		return fail(spec.Line, "key settings must be a mapping")
+	}
+
+	for i := 0; i+1 < len(spec.Content); i += 2 {
+		field, value := spec.Content[i], spec.Content[i+1]
+		if !manifestKeyFields[field.Value] {
+			return fail(field.Line, "unknown field %q", field.Value)
+		}
+		if value.Kind != yaml.ScalarNode {
+			return fail(value.Line, "%s must be a scalar", field.Value)
+		}
+		switch field.Value {
+		case "required":
+			if err := value.Decode(&key.Required); err != nil {
+				return fail(value.Line, "required must be true or false")
+			}
+		case "type":
+			if !isValueType(value.Value) {
+				return fail(value.Line, "unknown type %q: must be one of %s", value.Value, strings.Join(valueTypes, ", "))
+			}
+			key.Type = value.Value
+		case "regex":
+			re, err := regexp.Compile(value.Value)
+			if err != nil {
+				return fail(value.Line, "invalid regex: %v", err)
+			}
+			key.Regex = re
+		}
+	}
+	return key, nil
+}
+
+// validateManifest checks vars against m and reports every missing required key and
+// every value that does not match its declared type or regex. The values themselves are
+// never included in the errors.
+func validateManifest(m *manifest, vars []secretVar) error {
+	byKey := make(map[string]secretVar)
+	for _, v := range vars {
+		byKey[v.Key] = v
+	}
+
+	var errs []error
+	for _, key := range m.Keys {
+		v, ok := byKey[key.Name]
+		if !ok {
+			if key.Required {
+				errs = append(errs, &ManifestError{Key: key.Name, Line: key.Line, InManifest: true, Reason: "required key is missing"})
+			}
+			continue
+		}
+		if reason := validateValueType(key.Type, v.Value); reason != "" {
+			errs = append(errs, &ManifestError{Key: key.Name, Line: v.Line, Reason: reason})
+			continue
+		}
+		if key.Regex != nil && !key.Regex.MatchString(v.Value) {
+			errs = append(errs, &ManifestError{Key: key.Name, Line: v.Line, Reason: fmt.Sprintf("value does not match regex %s", key.Regex)})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isValueType(valueType string) bool {
+	for _, t := range valueTypes {
+		if t == valueType {
+			return true
+		}
+	}
+	return false
+}
+
+// validateValueType returns why value is not of type valueType, or an empty string if it is.
+func validateValueType(valueType, value string) string {
+	switch valueType {
+	case typeString:
+		return ""
+	case typeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "value is not an integer"
+		}
+	case typeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "value is not a boolean"
+		}
+	case typeURL:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return "value is not an absolute URL"
+		}
+	case typeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return "value is not a duration such as 30s or 1h5m"
+		}
+	case typeHex:
+		if !hexRe.MatchString(value) {
+			return "value is not a hex string"
+		}
+	case typeEthereumAddress:
+		if !ethereumAddressRe.MatchString(value) {
+			return "value is not an Ethereum address (0x followed by 40 hex digits)"
+		}
+	case typePrivateKey:
+		if !hexPrivateKeyRe.MatchString(value) && !isPEMPrivateKey(value) {
+			return "value is neither a 32-byte hex private key nor a PEM-encoded private key"
+		}
+	}
+	return ""
+}
+
+func isPEMPrivateKey(value string) bool {
+	block, _ := pem.Decode([]byte(value))
+	return block != nil && strings.HasSuffix(block.Type, "PRIVATE KEY")
+}
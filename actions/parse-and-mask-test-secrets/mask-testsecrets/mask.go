@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// minDerivedMaskLength is the shortest secret whose encodings are masked as well. The
+// encodings of very short values are common strings that would redact unrelated output.
+const minDerivedMaskLength = 8
+
+// maskVariants returns the secret followed by the encodings of it that tests commonly
+// print by accident: base64, URL-encoded, JSON-escaped and hex forms, and for URLs with
+// credentials the userinfo part and the password.
+func maskVariants(secret string) []string {
+	variants := []string{secret}
+	if len(secret) < minDerivedMaskLength {
+		return variants
+	}
+
+	raw := []byte(secret)
+	variants = append(variants,
+		base64.StdEncoding.EncodeToString(raw),
+		base64.RawStdEncoding.EncodeToString(raw),
+		base64.URLEncoding.EncodeToString(raw),
+		base64.RawURLEncoding.EncodeToString(raw),
+		url.QueryEscape(secret),
+		url.PathEscape(secret),
+		jsonEscape(secret, true),
+		jsonEscape(secret, false),
+		hex.EncodeToString(raw),
+		strings.ToUpper(hex.EncodeToString(raw)),
+	)
+
+	if u, err := url.Parse(secret); err == nil && u.User != nil {
+		credentials := []string{u.User.String()}
+		if password, ok := u.User.Password(); ok {
+			credentials = append(credentials, u.User.Username()+":"+password, password)
+		}
+		// Parts of the secret are held to the same length floor as the secret itself.
+		for _, c := range credentials {
+			if len(c) >= minDerivedMaskLength {
+				variants = append(variants, c)
+			}
+		}
+	}
+
+	return uniqueNonEmpty(variants)
+}
+
+// jsonEscape returns s as it appears inside a JSON string, optionally with the HTML-safe
+// escaping that encoding/json applies by default.
+func jsonEscape(s string, escapeHTML bool) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(escapeHTML)
+	if err := enc.Encode(s); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(buf.String(), "\n"), `"`), `"`)
+}
+
+func uniqueNonEmpty(values []string) []string {
+	seen := make(map[string]bool)
+	unique := values[:0]
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaskVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		expected []string
+	}{
+		{
+			name:     "Short Secret",
+			secret:   "abc",
+			expected: []string{"abc"},
+		},
+		{
+			name:   "Special Characters",
+			secret: `p@ss/w<rd>"&+?`,
+			expected: []string{
+				`p@ss/w<rd>"&+?`,
+				"cEBzcy93PHJkPiImKz8=",
+				"cEBzcy93PHJkPiImKz8",
+				"p%40ss%2Fw%3Crd%3E%22%26%2B%3F",
+				"p@ss%2Fw%3Crd%3E%22&+%3F",
+				`p@ss/w\u003crd\u003e\"\u0026+?`,
+				`p@ss/w<rd>\"&+?`,
+				"704073732f773c72643e22262b3f",
+				"704073732F773C72643E22262B3F",
+			},
+		},
+		{
+			name:   "URL-Safe Base64",
+			secret: "??>>??>>",
+			expected: []string{
+				"??>>??>>",
+				"Pz8+Pj8/Pj4=",
+				"Pz8+Pj8/Pj4",
+				"Pz8-Pj8_Pj4=",
+				"Pz8-Pj8_Pj4",
+				"%3F%3F%3E%3E%3F%3F%3E%3E",
+				`??\u003e\u003e??\u003e\u003e`,
+				"3f3f3e3e3f3f3e3e",
+				"3F3F3E3E3F3F3E3E",
+			},
+		},
+		{
+			name:   "URL with Credentials",
+			secret: "postgres://admin:s3cr%2Ft@db:5432/x",
+			expected: []string{
+				"postgres://admin:s3cr%2Ft@db:5432/x",
+				"cG9zdGdyZXM6Ly9hZG1pbjpzM2NyJTJGdEBkYjo1NDMyL3g=",
+				"cG9zdGdyZXM6Ly9hZG1pbjpzM2NyJTJGdEBkYjo1NDMyL3g",
+				"postgres%3A%2F%2Fadmin%3As3cr%252Ft%40db%3A5432%2Fx",
+				"postgres:%2F%2Fadmin:s3cr%252Ft@db:5432%2Fx",
+				"706f7374677265733a2f2f61646d696e3a73336372253246744064623a353433322f78",
+				"706F7374677265733A2F2F61646D696E3A73336372253246744064623A353433322F78",
+				"admin:s3cr%2Ft",
+				"admin:s3cr/t",
+			},
+		},
+		{
+			name:   "URL with One-Character Password",
+			secret: "postgres://u:x@db.example.com:5432/app",
+			expected: []string{
+				"postgres://u:x@db.example.com:5432/app",
+				"cG9zdGdyZXM6Ly91OnhAZGIuZXhhbXBsZS5jb206NTQzMi9hcHA=",
+				"cG9zdGdyZXM6Ly91OnhAZGIuZXhhbXBsZS5jb206NTQzMi9hcHA",
+				"postgres%3A%2F%2Fu%3Ax%40db.example.com%3A5432%2Fapp",
+				"postgres:%2F%2Fu:x@db.example.com:5432%2Fapp",
+				"706f7374677265733a2f2f753a784064622e6578616d706c652e636f6d3a353433322f617070",
+				"706F7374677265733A2F2F753A784064622E6578616D706C652E636F6D3A353433322F617070",
+			},
+		},
+		{
+			name:   "URL with Token as Username",
+			secret: "https://ghp_token@github.com",
+			expected: []string{
+				"https://ghp_token@github.com",
+				"aHR0cHM6Ly9naHBfdG9rZW5AZ2l0aHViLmNvbQ==",
+				"aHR0cHM6Ly9naHBfdG9rZW5AZ2l0aHViLmNvbQ",
+				"https%3A%2F%2Fghp_token%40github.com",
+				"https:%2F%2Fghp_token@github.com",
+				"68747470733a2f2f6768705f746f6b656e406769746875622e636f6d",
+				"68747470733A2F2F6768705F746F6B656E406769746875622E636F6D",
+				"ghp_token",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskVariants(tt.secret); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("maskVariants(%q) = %q, want %q", tt.secret, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaskSecretDerivedMultiline(t *testing.T) {
+	secret := "-----BEGIN KEY-----\nMIIBVQ==\n-----END KEY-----"
+	var buf bytes.Buffer
+	maskSecret(&buf, "KEY", secret)
+
+	masks := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")[1:] {
+		if !strings.HasPrefix(line, "::add-mask::") {
+			t.Fatalf("maskSecret() wrote %q, want only ::add-mask:: commands after the description", line)
+		}
+		masks[strings.TrimPrefix(line, "::add-mask::")] = true
+	}
+	for _, want := range []string{
+		"-----BEGIN KEY-----",
+		"MIIBVQ==",
+		"-----END KEY-----",
+		`-----BEGIN KEY-----\nMIIBVQ==\n-----END KEY-----`,
+		"LS0tLS1CRUdJTiBLRVktLS0tLQpNSUlCVlE9PQotLS0tLUVORCBLRVktLS0tLQ==",
+	} {
+		if !masks[want] {
+			t.Errorf("maskSecret() did not mask %q", want)
+		}
+	}
+}
+
+func TestMaskSecretEscapesCommandData(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		expected []string
+	}{
+		{
+			name:     "Percent Sign",
+			secret:   "p%40ss",
+			expected: []string{"p%2540ss"},
+		},
+		{
+			name:     "Carriage Return",
+			secret:   "a\rb",
+			expected: []string{"a%0Db"},
+		},
+		{
+			name:   "URL-Encoded Variants",
+			secret: "postgres://admin:s3cr%2Ft@db:5432/x",
+			expected: []string{
+				"postgres://admin:s3cr%252Ft@db:5432/x",
+				"postgres%253A%252F%252Fadmin%253As3cr%25252Ft%2540db%253A5432%252Fx",
+				"postgres:%252F%252Fadmin:s3cr%25252Ft@db:5432%252Fx",
+				"admin:s3cr%252Ft",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			maskSecret(&buf, "KEY", tt.secret)
+
+			masks := make(map[string]bool)
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.HasPrefix(line, "::add-mask::") {
+					masks[strings.TrimPrefix(line, "::add-mask::")] = true
+				}
+			}
+			for _, want := range tt.expected {
+				if !masks[want] {
+					t.Errorf("maskSecret(%q) did not write ::add-mask::%s, got %q", tt.secret, want, buf.String())
+				}
+			}
+		})
+	}
+}